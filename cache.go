@@ -0,0 +1,162 @@
+package tdag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// defaultCacheDir is the conventional cache directory name suggested to
+// WithCacheDir. Caching is opt-in: a TDag with no WithCacheDir call has
+// cacheDir == "" and never reads or writes a manifest.
+const defaultCacheDir = ".tdag-cache"
+
+// noCacheEnv disables caching entirely when set to "1", regardless of
+// WithCacheDir.
+const noCacheEnv = "TDAG_NO_CACHE"
+
+// cacheManifest is the persisted record of a node's last successful run.
+// It is compared against the node's current Fn and declared dependencies
+// to decide whether a later run can skip Fn entirely.
+type cacheManifest struct {
+	NodeID         string            `json:"node_id"`
+	FnSourceHash   string            `json:"fn_source_hash"`
+	FileHashes     map[string]string `json:"file_hashes"`
+	AbsentFiles    []string          `json:"absent_files"`
+	StoreReadsHash string            `json:"store_reads_hash"`
+	ExitStatus     string            `json:"exit_status"`
+}
+
+// cacheEnabled reports whether result caching is active for this TDag.
+func (d *TDag) cacheEnabled() bool {
+	return d.cacheDir != "" && os.Getenv(noCacheEnv) != "1"
+}
+
+// cachePath returns the manifest path for a node.
+func (d *TDag) cachePath(nodeID string) string {
+	return filepath.Join(d.cacheDir, nodeID+".json")
+}
+
+// loadManifest reads a node's cache entry, if one exists.
+func (d *TDag) loadManifest(nodeID string) (*cacheManifest, bool) {
+	b, err := os.ReadFile(d.cachePath(nodeID))
+	if err != nil {
+		return nil, false
+	}
+	var m cacheManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, false
+	}
+	return &m, true
+}
+
+// saveManifest hashes a node's declared dependencies and persists the
+// result manifest for a passing run. storeReadsHash must be the store
+// snapshot taken before n.Fn ran, the same snapshot cacheHit will compare
+// against on a later run - hashing runCtx.Store after Fn has returned
+// would include the node's own writes and never match again.
+func (d *TDag) saveManifest(n *TNode, runCtx *TestContext, storeReadsHash string) error {
+	fileHashes := make(map[string]string, len(runCtx.ifchange))
+	for _, path := range runCtx.ifchange {
+		h, err := hashFile(path)
+		if err != nil {
+			return fmt.Errorf("tdag: hashing %s for node %s: %w", path, n.ID, err)
+		}
+		fileHashes[path] = h
+	}
+
+	m := &cacheManifest{
+		NodeID:         n.ID,
+		FnSourceHash:   fnSourceHash(n.Fn, n.FnVersion),
+		FileHashes:     fileHashes,
+		AbsentFiles:    append([]string(nil), runCtx.ifcreate...),
+		StoreReadsHash: storeReadsHash,
+		ExitStatus:     "passed",
+	}
+
+	if err := os.MkdirAll(d.cacheDir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.cachePath(n.ID), b, 0644)
+}
+
+// cacheHit reports whether a node's prior manifest is still valid: its Fn
+// identity is unchanged, the run it recorded passed, every Ifchange file
+// still hashes the same, every Ifcreate file is still absent, and the
+// store holds the same contents it did just before that run's Fn, so an
+// upstream node that silently changed what it writes busts the cache too.
+func cacheHit(m *cacheManifest, n *TNode, storeReadsHash string) bool {
+	if m.ExitStatus != "passed" {
+		return false
+	}
+	if m.FnSourceHash != fnSourceHash(n.Fn, n.FnVersion) {
+		return false
+	}
+	if m.StoreReadsHash != storeReadsHash {
+		return false
+	}
+	for path, want := range m.FileHashes {
+		got, err := hashFile(path)
+		if err != nil || got != want {
+			return false
+		}
+	}
+	for _, path := range m.AbsentFiles {
+		if _, err := os.Stat(path); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// fnSourceHash identifies a node's Fn well enough to detect that it
+// changed. Go gives no reliable way to hash a function's actual source,
+// so this combines the function's fully-qualified runtime name with a
+// user-supplied version string, since a body edit that keeps the name
+// (e.g. an anonymous closure, named by position like pkg.TestX.func1)
+// would otherwise hash the same.
+func fnSourceHash(fn TestFn, version string) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	sum := sha256.Sum256([]byte(name + "@" + version))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the SHA-256 hash of a file's contents.
+func hashFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// snapshotHash hashes the store's full contents, giving the cache a
+// coarse way to notice that an upstream node wrote something different
+// even when no declared file changed. It is store-wide rather than
+// scoped to the keys a node actually read.
+func (s *TStore) snapshotHash() string {
+	snap := s.Snapshot()
+
+	keys := make([]string, 0, len(snap))
+	for k := range snap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%v\n", k, snap[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
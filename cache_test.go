@@ -0,0 +1,166 @@
+package tdag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheIsOptInByDefault(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	runs := 0
+	build := func() *TDag {
+		d := NewTDag(t)
+		d.AddNode("a", func(ctx *TestContext) { runs++ })
+		return d
+	}
+
+	build().RunTests(t)
+	build().RunTests(t)
+
+	if runs != 2 {
+		t.Fatalf("expected caching to be off by default, got %d runs", runs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultCacheDir)); err == nil {
+		t.Fatalf("expected no cache dir to be written without WithCacheDir")
+	}
+}
+
+func TestCacheHitSkipsFn(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	file := filepath.Join(dir, "input.txt")
+	os.WriteFile(file, []byte("v1"), 0644)
+
+	runs := 0
+	build := func() *TDag {
+		d := NewTDag(t, WithCacheDir(cacheDir))
+		d.AddNode("a", func(ctx *TestContext) {
+			runs++
+			ctx.Ifchange(file)
+		})
+		return d
+	}
+
+	build().RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected 1 run, got %d", runs)
+	}
+
+	build().RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected cache hit to skip Fn, got %d runs", runs)
+	}
+
+	os.WriteFile(file, []byte("v2"), 0644)
+	build().RunTests(t)
+	if runs != 2 {
+		t.Fatalf("expected file change to invalidate cache, got %d runs", runs)
+	}
+}
+
+func TestCacheInvalidatedByStoreChange(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+
+	runs := 0
+	build := func(storeValue string) *TDag {
+		d := NewTDag(t, WithCacheDir(cacheDir))
+		d.Ctx.Store.Set("upstream", storeValue)
+		d.AddNode("a", func(ctx *TestContext) { runs++ })
+		return d
+	}
+
+	build("v1").RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected 1 run, got %d", runs)
+	}
+
+	build("v1").RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected cache hit with unchanged store, got %d runs", runs)
+	}
+
+	build("v2").RunTests(t)
+	if runs != 2 {
+		t.Fatalf("expected store change to invalidate cache, got %d runs", runs)
+	}
+}
+
+// TestCacheHitSurvivesNodesOwnStoreWrite is a regression test: saveManifest
+// used to hash the store after Fn had already run, so a node's own write
+// to the store was baked into its manifest's StoreReadsHash - and since
+// runNode always re-hashes the store before Fn runs on the next attempt,
+// that pre-run hash could never match the post-run hash the manifest
+// recorded, so the node missed its own cache on every single run.
+func TestCacheHitSurvivesNodesOwnStoreWrite(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+
+	runs := 0
+	build := func() *TDag {
+		d := NewTDag(t, WithCacheDir(cacheDir))
+		d.AddNode("a", func(ctx *TestContext) {
+			runs++
+			ctx.Store.Set("token", "abc")
+		})
+		return d
+	}
+
+	build().RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected 1 run, got %d", runs)
+	}
+
+	build().RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected a's own store write not to defeat its own cache, got %d runs", runs)
+	}
+
+	build().RunTests(t)
+	if runs != 1 {
+		t.Fatalf("expected the cache hit to keep holding across repeated runs, got %d runs", runs)
+	}
+}
+
+// TestCacheHitChainWhereUpstreamWrites is a regression test for the
+// cascading form of the same bug: in a chain a->b where a writes a key
+// that b never touches, a used to never hit, since a's own write kept
+// busting its own manifest on every run.
+func TestCacheHitChainWhereUpstreamWrites(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+
+	runsA := 0
+	build := func() *TDag {
+		d := NewTDag(t, WithCacheDir(cacheDir))
+		d.AddNode("a", func(ctx *TestContext) {
+			runsA++
+			ctx.Store.Set("verified", true)
+		})
+		d.AddNode("b", func(ctx *TestContext) {})
+		if _, err := d.AddEdge("a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	build().RunTests(t)
+	if runsA != 1 {
+		t.Fatalf("expected 1 run of a, got %d", runsA)
+	}
+
+	build().RunTests(t)
+	if runsA != 1 {
+		t.Fatalf("expected a to cache hit on the second run, got %d runs", runsA)
+	}
+}
@@ -0,0 +1,90 @@
+package tdag
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Constraint gates whether an edge's target may run once it becomes
+// available. It inspects the TestContext and the nodes on either side of
+// the edge and reports whether the edge is currently satisfied, along
+// with a human-readable message to use when it is not.
+type Constraint interface {
+	Satisfied(ctx *TestContext, from, to *TNode) (bool, string, error)
+}
+
+type storeKeyPresent struct {
+	key string
+}
+
+// StoreKeyPresent is satisfied once the given key has been written to the
+// store.
+func StoreKeyPresent(key string) Constraint {
+	return storeKeyPresent{key: key}
+}
+
+func (c storeKeyPresent) Satisfied(ctx *TestContext, from, to *TNode) (bool, string, error) {
+	if _, err := ctx.Store.Get(c.key); err != nil {
+		return false, fmt.Sprintf("store key %q is not present", c.key), nil
+	}
+	return true, "", nil
+}
+
+type storeKeyEquals struct {
+	key   string
+	value any
+}
+
+// StoreKeyEquals is satisfied once the given key has been written to the
+// store with exactly the given value.
+func StoreKeyEquals(key string, v any) Constraint {
+	return storeKeyEquals{key: key, value: v}
+}
+
+func (c storeKeyEquals) Satisfied(ctx *TestContext, from, to *TNode) (bool, string, error) {
+	got, err := ctx.Store.Get(c.key)
+	if err != nil {
+		return false, fmt.Sprintf("store key %q is not present", c.key), nil
+	}
+	if !reflect.DeepEqual(got, c.value) {
+		return false, fmt.Sprintf("store key %q = %v, want %v", c.key, got, c.value), nil
+	}
+	return true, "", nil
+}
+
+type nodePassedConstraint struct {
+	id string
+}
+
+// NodePassed is satisfied once the named node has run and passed,
+// regardless of whether it sits on the edge it gates.
+func NodePassed(id string) Constraint {
+	return nodePassedConstraint{id: id}
+}
+
+func (c nodePassedConstraint) Satisfied(ctx *TestContext, from, to *TNode) (bool, string, error) {
+	if ctx.dag == nil {
+		return false, "", fmt.Errorf("node %s result is not available", c.id)
+	}
+	passed, ran := ctx.dag.nodePassed(c.id)
+	if !ran {
+		return false, fmt.Sprintf("node %s has not run yet", c.id), nil
+	}
+	if !passed {
+		return false, fmt.Sprintf("node %s did not pass", c.id), nil
+	}
+	return true, "", nil
+}
+
+type customConstraint struct {
+	fn func(ctx *TestContext, from, to *TNode) (bool, string, error)
+}
+
+// Custom wraps an arbitrary function as a Constraint.
+func Custom(fn func(ctx *TestContext, from, to *TNode) (bool, string, error)) Constraint {
+	return customConstraint{fn: fn}
+}
+
+func (c customConstraint) Satisfied(ctx *TestContext, from, to *TNode) (bool, string, error) {
+	return c.fn(ctx, from, to)
+}
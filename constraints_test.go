@@ -0,0 +1,67 @@
+package tdag
+
+import "testing"
+
+func TestStoreKeyEqualsUncomparableValue(t *testing.T) {
+	d := NewTDag(t)
+	d.Ctx.Store.Set("tags", []string{"a", "b"})
+
+	constraint := StoreKeyEquals("tags", []string{"a", "b"})
+	ok, _, err := constraint.Satisfied(d.Ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected equal slices to satisfy StoreKeyEquals")
+	}
+
+	mismatch := StoreKeyEquals("tags", []string{"a", "c"})
+	ok, msg, err := mismatch.Satisfied(d.Ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected mismatched slices to fail StoreKeyEquals")
+	}
+	if msg == "" {
+		t.Fatalf("expected a message explaining the mismatch")
+	}
+}
+
+func TestStoreKeyPresent(t *testing.T) {
+	d := NewTDag(t)
+	constraint := StoreKeyPresent("email")
+
+	ok, _, err := constraint.Satisfied(d.Ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected unset key to fail StoreKeyPresent")
+	}
+
+	d.Ctx.Store.Set("email", "a@example.com")
+	ok, _, err = constraint.Satisfied(d.Ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected set key to satisfy StoreKeyPresent")
+	}
+}
+
+func TestNodePassedConstraint(t *testing.T) {
+	d := NewTDag(t)
+	d.AddNode("a", func(ctx *TestContext) {})
+	d.AddNode("b", func(ctx *TestContext) {})
+	if _, err := d.AddEdgeWith("a", "b", NodePassed("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	d.RunTests(t)
+
+	passed, ran := d.nodePassed("b")
+	if !ran || !passed {
+		t.Fatalf("expected b to run and pass once a passed, got ran=%v passed=%v", ran, passed)
+	}
+}
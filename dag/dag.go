@@ -100,9 +100,16 @@ func (d *Dag[T]) Test(t *testing.T) {
 	}
 }
 
+// createsCycle reports whether adding an edge from->to would create a
+// cycle, i.e. whether to can already reach from. Note the swapped
+// argument order in the detectCycle call below: it walks forward from to
+// looking for from, not the other way around - passing them in from->to
+// order here would check whether from can already reach to, which
+// rejects redundant edges like a->c next to a->b->c and lets real
+// cycles through.
 func (d *Dag[T]) createsCycle(from, to *Node) bool {
 	visited := make(map[string]bool)
-	return d.detectCycle(from, to, visited)
+	return d.detectCycle(to, from, visited)
 }
 
 func (d *Dag[T]) detectCycle(start, target *Node, visited map[string]bool) bool {
@@ -272,6 +279,109 @@ func (d *Dag[T]) collectDependencies(nodeID string, collected map[string]bool) {
 	}
 }
 
+// RunOption configures a Run invocation.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	concurrency int
+}
+
+// WithConcurrency caps the number of nodes Run executes concurrently.
+// Zero (the default) leaves the worker pool unbounded, spawning one
+// goroutine per ready node as RunTests and RunTo do.
+func WithConcurrency(n int) RunOption {
+	return func(c *runConfig) {
+		c.concurrency = n
+	}
+}
+
+// Run runs the tests in topological order like RunTests, but accepts
+// RunOptions. WithConcurrency caps the number of nodes run concurrently
+// instead of spawning one goroutine per ready node.
+func (d *Dag[T]) Run(t *testing.T, opts ...RunOption) {
+	cfg := &runConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var sem chan struct{}
+	if cfg.concurrency > 0 {
+		sem = make(chan struct{}, cfg.concurrency)
+	}
+
+	// Create dependency graph and track in-degree for each node
+	inDegree := make(map[string]int)
+	outEdges := make(map[string][]*Node)
+
+	// Initialize in-degree counts and build adjacency list
+	for _, node := range d.Nodes {
+		inDegree[node.ID] = 0
+	}
+	for _, edge := range d.Edges {
+		inDegree[edge.Right.ID]++
+		outEdges[edge.Left.ID] = append(outEdges[edge.Left.ID], edge.Right)
+	}
+
+	// Keep track of completed nodes
+	completed := make(map[string]bool)
+	var completedMux sync.Mutex
+
+	// Run tests in topological order.
+	for {
+		// Find nodes with no dependencies.
+		var available []*Node
+		for _, node := range d.Nodes {
+			completedMux.Lock()
+			if !completed[node.ID] && inDegree[node.ID] == 0 {
+				available = append(available, node)
+			}
+			completedMux.Unlock()
+		}
+
+		// If no nodes are available but we haven't processed all nodes, we have a cycle.
+		if len(available) == 0 {
+			var remaining []string
+			completedMux.Lock()
+			for _, node := range d.Nodes {
+				if !completed[node.ID] {
+					remaining = append(remaining, node.ID)
+				}
+			}
+			completedMux.Unlock()
+
+			if len(remaining) > 0 {
+				t.Fatalf("Dependency cycle detected. Remaining nodes: %v", remaining)
+			}
+			break
+		}
+
+		// Run available tests, capped by sem when it's set.
+		var wg sync.WaitGroup
+		for _, node := range available {
+			if sem != nil {
+				sem <- struct{}{}
+			}
+			wg.Add(1)
+			go func(n *Node) {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				t.Run(n.ID, func(t *testing.T) {
+					n.Fn(t)
+					completedMux.Lock()
+					completed[n.ID] = true
+					for _, dependent := range outEdges[n.ID] {
+						inDegree[dependent.ID]--
+					}
+					completedMux.Unlock()
+				})
+			}(node)
+		}
+		wg.Wait()
+	}
+}
+
 // RunTests runs the tests in topological order.
 //
 // Arguments:
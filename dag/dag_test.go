@@ -1,6 +1,8 @@
 package dag
 
 import (
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -84,3 +86,54 @@ func Test(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCreatesCycleRejectsCycle(t *testing.T) {
+	dag := NewDag[struct{}]()
+
+	dag.AddNode("a", func(t *testing.T) {})
+	dag.AddNode("b", func(t *testing.T) {})
+	dag.AddNode("c", func(t *testing.T) {})
+
+	if _, err := dag.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dag.AddEdge("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dag.AddEdge("c", "a"); err == nil {
+		t.Fatal("expected adding c->a to be rejected as a cycle")
+	}
+}
+
+// TestRunWithConcurrencyBoundsParallelism gives Run a batch of independent
+// nodes (no edges between them, so all become available at once) and
+// checks that WithConcurrency actually caps how many run at the same
+// time, rather than spawning one goroutine per ready node.
+func TestRunWithConcurrencyBoundsParallelism(t *testing.T) {
+	dag := NewDag[struct{}]()
+
+	var current, max int32
+	for i := 0; i < 10; i++ {
+		dag.AddNode(fmt.Sprintf("n%d", i), func(t *testing.T) {
+			n := atomic.AddInt32(&current, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		})
+	}
+
+	dag.Run(t, WithConcurrency(2))
+
+	if max > 2 {
+		t.Fatalf("expected at most 2 nodes running at once, saw %d", max)
+	}
+	if max < 2 {
+		t.Fatalf("expected concurrency to actually reach the cap of 2, saw %d", max)
+	}
+}
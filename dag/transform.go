@@ -0,0 +1,170 @@
+package dag
+
+// GraphTransformer mutates a Dag in place, modeled after Terraform's graph
+// transformer pattern: prune nodes or rewire edges before the graph is
+// scheduled.
+type GraphTransformer[T any] interface {
+	Transform(d *Dag[T]) error
+}
+
+// TransformerPipeline runs an ordered list of GraphTransformers against a
+// Dag, stopping at the first error.
+type TransformerPipeline[T any] struct {
+	Transformers []GraphTransformer[T]
+}
+
+// NewTransformerPipeline builds a TransformerPipeline from the given
+// transformers, run in the order given.
+func NewTransformerPipeline[T any](transformers ...GraphTransformer[T]) *TransformerPipeline[T] {
+	return &TransformerPipeline[T]{Transformers: transformers}
+}
+
+// Transform runs each transformer in order, stopping and returning the
+// first error encountered.
+func (p *TransformerPipeline[T]) Transform(d *Dag[T]) error {
+	for _, t := range p.Transformers {
+		if err := t.Transform(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply runs transformers against the graph in order. Call it before
+// Test/RunTo so the graph that gets scheduled reflects any pruning or
+// rewiring the transformers perform.
+func (d *Dag[T]) Apply(transformers ...GraphTransformer[T]) error {
+	return NewTransformerPipeline(transformers...).Transform(d)
+}
+
+// PruneNoopTransformer drops nodes with no test function, rewiring their
+// in-edges directly to their out-edges so downstream dependents are not
+// orphaned.
+type PruneNoopTransformer[T any] struct{}
+
+// Transform removes every node whose Fn is nil, reconnecting the graph
+// around it.
+func (PruneNoopTransformer[T]) Transform(d *Dag[T]) error {
+	var noop []*Node
+	for _, n := range d.Nodes {
+		if n.Fn == nil {
+			noop = append(noop, n)
+		}
+	}
+
+	for _, n := range noop {
+		var in, out []*Node
+		for _, e := range d.Edges {
+			if e.Right.ID == n.ID {
+				in = append(in, e.Left)
+			}
+			if e.Left.ID == n.ID {
+				out = append(out, e.Right)
+			}
+		}
+
+		for _, u := range in {
+			for _, w := range out {
+				if u.ID == w.ID || d.hasEdge(u.ID, w.ID) || d.createsCycle(u, w) {
+					continue
+				}
+				d.Edges = append(d.Edges, &Edge{Left: u, Right: w})
+			}
+		}
+
+		d.removeNode(n.ID)
+	}
+
+	return nil
+}
+
+// TransitiveReductionTransformer removes redundant edges A->C when a
+// longer path A->B->...->C already exists, leaving the schedule unchanged
+// while trimming edges that add nothing but noise.
+type TransitiveReductionTransformer[T any] struct{}
+
+// Transform drops every edge that is implied by a longer path between the
+// same two nodes.
+func (TransitiveReductionTransformer[T]) Transform(d *Dag[T]) error {
+	reachable := make(map[string]map[string]bool, len(d.Nodes))
+	for _, n := range d.Nodes {
+		reachable[n.ID] = d.reachableFrom(n.ID)
+	}
+
+	var kept []*Edge
+	for _, e := range d.Edges {
+		redundant := false
+		for _, out := range d.outEdgesOf(e.Left.ID) {
+			if out.Right.ID == e.Right.ID {
+				continue
+			}
+			if reachable[out.Right.ID][e.Right.ID] {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			kept = append(kept, e)
+		}
+	}
+	d.Edges = kept
+	return nil
+}
+
+// outEdgesOf returns the edges leaving the node with the given id.
+func (d *Dag[T]) outEdgesOf(id string) []*Edge {
+	var out []*Edge
+	for _, e := range d.Edges {
+		if e.Left.ID == id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// reachableFrom returns every node id reachable from id via one or more
+// edges.
+func (d *Dag[T]) reachableFrom(id string) map[string]bool {
+	seen := make(map[string]bool)
+	var visit func(string)
+	visit = func(cur string) {
+		for _, e := range d.Edges {
+			if e.Left.ID == cur && !seen[e.Right.ID] {
+				seen[e.Right.ID] = true
+				visit(e.Right.ID)
+			}
+		}
+	}
+	visit(id)
+	return seen
+}
+
+// hasEdge reports whether an edge from from to to already exists.
+func (d *Dag[T]) hasEdge(from, to string) bool {
+	for _, e := range d.Edges {
+		if e.Left.ID == from && e.Right.ID == to {
+			return true
+		}
+	}
+	return false
+}
+
+// removeNode drops the node with the given id along with every edge that
+// touches it.
+func (d *Dag[T]) removeNode(id string) {
+	nodes := d.Nodes[:0]
+	for _, n := range d.Nodes {
+		if n.ID != id {
+			nodes = append(nodes, n)
+		}
+	}
+	d.Nodes = nodes
+
+	edges := d.Edges[:0]
+	for _, e := range d.Edges {
+		if e.Left.ID != id && e.Right.ID != id {
+			edges = append(edges, e)
+		}
+	}
+	d.Edges = edges
+}
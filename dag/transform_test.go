@@ -0,0 +1,60 @@
+package dag
+
+import (
+	"testing"
+)
+
+func TestPruneNoopTransformer(t *testing.T) {
+	dag := NewDag[struct{}]()
+
+	dag.AddNode("a", func(t *testing.T) {})
+	dag.AddNode("noop", nil)
+	dag.AddNode("b", func(t *testing.T) {})
+
+	if _, err := dag.AddEdge("a", "noop"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dag.AddEdge("noop", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dag.Apply(PruneNoopTransformer[struct{}]{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dag.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes after pruning, got %d", len(dag.Nodes))
+	}
+	if !dag.hasEdge("a", "b") {
+		t.Fatalf("expected rewired edge a->b after pruning noop")
+	}
+}
+
+func TestTransitiveReductionTransformer(t *testing.T) {
+	dag := NewDag[struct{}]()
+
+	dag.AddNode("a", func(t *testing.T) {})
+	dag.AddNode("b", func(t *testing.T) {})
+	dag.AddNode("c", func(t *testing.T) {})
+
+	if _, err := dag.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dag.AddEdge("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dag.AddEdge("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dag.Apply(TransitiveReductionTransformer[struct{}]{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if dag.hasEdge("a", "c") {
+		t.Fatalf("expected redundant edge a->c to be removed")
+	}
+	if !dag.hasEdge("a", "b") || !dag.hasEdge("b", "c") {
+		t.Fatalf("expected a->b and b->c to survive transitive reduction")
+	}
+}
@@ -12,10 +12,15 @@ import (
 func TestDag(t *testing.T) {
 	dag := tdag.NewTDag(t)
 
+	// componentsScope holds fixtures private to the components.* nodes,
+	// set up by components.create below; it's captured here so sibling
+	// nodes can reach it without going through the dag-wide Store.
+	var componentsScope *tdag.TStore
+
 	// This will be run first, before any node is executed.
 	dag.Setup(func(ctx *tdag.TestContext) {
-		ctx.Store.Set("setup", true)
-		ctx.Store.Set("email", fmt.Sprintf("test-%d@example.com", time.Now().Unix()))
+		tdag.Set(ctx.Store, "setup", true)
+		tdag.Set(ctx.Store, "email", fmt.Sprintf("test-%d@example.com", time.Now().Unix()))
 	})
 
 	// This will be run last, after all nodes are executed.
@@ -32,7 +37,7 @@ func TestDag(t *testing.T) {
 	})
 
 	dag.AddNode("registration.register", func(ctx *tdag.TestContext) {
-		setup, err := ctx.Store.Get("setup")
+		setup, err := tdag.Get[bool](ctx.Store, "setup")
 		assert.NoError(ctx.T, err)
 		assert.Equal(ctx.T, setup, true)
 		ctx.T.Log("registration.register")
@@ -43,8 +48,7 @@ func TestDag(t *testing.T) {
 	})
 
 	dag.AddNode("session.login", func(ctx *tdag.TestContext) {
-		email, err := ctx.Store.Get("email")
-		assert.NoError(ctx.T, err)
+		email := tdag.MustGet[string](ctx.Store, "email")
 		ctx.T.Logf("session.login: %s", email)
 	})
 
@@ -53,11 +57,14 @@ func TestDag(t *testing.T) {
 	})
 
 	dag.AddNode("components.create", func(ctx *tdag.TestContext) {
+		componentsScope = ctx.Store.Scope("components")
+		tdag.Set(componentsScope, "componentID", "c-123")
 		ctx.T.Log("components.create")
 	})
 
 	dag.AddNode("components.get", func(ctx *tdag.TestContext) {
-		ctx.T.Log("components.create")
+		id := tdag.MustGet[string](componentsScope, "componentID")
+		ctx.T.Logf("components.get: %s", id)
 	})
 
 	dag.AddNode("components.change.create", func(ctx *tdag.TestContext) {
@@ -69,7 +76,10 @@ func TestDag(t *testing.T) {
 	})
 
 	dag.AddNode("components.tags.delete", func(ctx *tdag.TestContext) {
-		ctx.T.Log("tags.delete")
+		// Block on the same write components.get read directly, instead
+		// of polling Get.
+		id := <-componentsScope.Watch("componentID")
+		ctx.T.Logf("tags.delete: %s", id)
 	})
 
 	dag.AddNode("components.tags.get", func(ctx *tdag.TestContext) {
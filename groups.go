@@ -0,0 +1,284 @@
+package tdag
+
+import (
+	"strings"
+	"time"
+)
+
+// GroupRule decides which directly-connected nodes may be fused into a
+// single fixture, and how to build the fused group's TestFn.
+type GroupRule struct {
+	// Match reports whether b may join a's group, given the edge a->b.
+	Match func(a, b *TNode) bool
+	// Merge builds the TestFn for a group, given its members in chain
+	// order (head to tail).
+	Merge func(group []*TNode) TestFn
+}
+
+// GroupMerge records one chain of nodes AutoGroup fused into a single
+// synthetic node.
+type GroupMerge struct {
+	NodeID  string
+	Members []string
+}
+
+// GroupReport lists every merge AutoGroup performed, for debugging and
+// test assertions.
+type GroupReport struct {
+	Merges []GroupMerge
+}
+
+// AutoGroup fuses chains of nodes matched by the given rules into single
+// synthetic nodes before scheduling, so expensive shared setup that's
+// currently repeated once per node only runs once per chain. A chain is
+// only eligible if every node in it has no external in-edges except at
+// its head and no external out-edges except at its tail; contracting
+// such a chain can never introduce a cycle or reorder anything, since the
+// synthetic node simply inherits the chain's existing boundary edges.
+//
+// Run AutoGroup before RunTests/RunTo/Run so the graph they schedule
+// reflects the fused nodes.
+func (d *TDag) AutoGroup(rules ...GroupRule) *GroupReport {
+	report := &GroupReport{}
+	for _, rule := range rules {
+		d.applyGroupRule(rule, report)
+	}
+	return report
+}
+
+// applyGroupRule finds every maximal chain the rule matches and collapses
+// each into a synthetic node, recording the merge in report.
+func (d *TDag) applyGroupRule(rule GroupRule, report *GroupReport) {
+	for {
+		chain := d.findGroupChain(rule)
+		if chain == nil {
+			return
+		}
+		d.mergeChain(chain, rule, report)
+	}
+}
+
+// findGroupChain returns the first maximal chain of two or more nodes
+// connected edge-to-edge where each link is its source's only out-edge
+// and its target's only in-edge, carries no constraints, and is approved
+// by rule.Match, or nil if
+// no such chain remains. Candidate chains are walked forward from true
+// chain roots (nodes no mergeable edge points at), so the result doesn't
+// depend on the order nodes happen to appear in d.Nodes.
+func (d *TDag) findGroupChain(rule GroupRule) []*TNode {
+	outDeg := make(map[string]int)
+	inDeg := make(map[string]int)
+	outEdge := make(map[string]*TEdge)
+	for _, e := range d.Edges {
+		outDeg[e.Left.ID]++
+		inDeg[e.Right.ID]++
+		outEdge[e.Left.ID] = e
+	}
+
+	mergeable := func(e *TEdge) bool {
+		return outDeg[e.Left.ID] == 1 && inDeg[e.Right.ID] == 1 && len(e.Constraints) == 0 && rule.Match(e.Left, e.Right)
+	}
+
+	isTarget := make(map[string]bool)
+	for _, e := range d.Edges {
+		if mergeable(e) {
+			isTarget[e.Right.ID] = true
+		}
+	}
+
+	for _, head := range d.Nodes {
+		if isTarget[head.ID] {
+			// head is itself the mergeable successor of another node, so
+			// it belongs to a chain rooted further back, not a new one.
+			continue
+		}
+		e, ok := outEdge[head.ID]
+		if !ok || !mergeable(e) {
+			continue
+		}
+
+		chain := []*TNode{head}
+		seen := map[string]bool{head.ID: true}
+		cur := head
+		for {
+			e, ok := outEdge[cur.ID]
+			if !ok || seen[e.Right.ID] || !mergeable(e) {
+				break
+			}
+			chain = append(chain, e.Right)
+			seen[e.Right.ID] = true
+			cur = e.Right
+		}
+		if len(chain) > 1 {
+			return chain
+		}
+	}
+	return nil
+}
+
+// mergeChain collapses chain into a single synthetic node, rewiring the
+// chain head's external in-edges and the chain tail's external out-edges
+// onto it, and records the merge in report.
+func (d *TDag) mergeChain(chain []*TNode, rule GroupRule, report *GroupReport) {
+	members := make(map[string]bool, len(chain))
+	ids := make([]string, len(chain))
+	for i, n := range chain {
+		members[n.ID] = true
+		ids[i] = n.ID
+	}
+	retries, backoff := maxRetries(chain)
+	synthetic := &TNode{
+		ID:            strings.Join(ids, "+"),
+		Fn:            rule.Merge(chain),
+		Timeout:       minPositiveTimeout(chain),
+		Retries:       retries,
+		Backoff:       backoff,
+		FailurePolicy: strictestFailurePolicy(chain),
+	}
+
+	var edges []*TEdge
+	for _, e := range d.Edges {
+		switch {
+		case members[e.Left.ID] && members[e.Right.ID]:
+			// Internal edge; dropped, since the chain now runs as one node.
+			continue
+		case members[e.Right.ID]:
+			// External in-edge onto the chain; must land on the head.
+			e.Right = synthetic
+			edges = append(edges, e)
+		case members[e.Left.ID]:
+			// External out-edge off the chain; must leave from the tail.
+			e.Left = synthetic
+			edges = append(edges, e)
+		default:
+			edges = append(edges, e)
+		}
+	}
+	d.Edges = edges
+
+	var nodes []*TNode
+	inserted := false
+	for _, n := range d.Nodes {
+		if members[n.ID] {
+			if !inserted {
+				nodes = append(nodes, synthetic)
+				inserted = true
+			}
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	d.Nodes = nodes
+
+	report.Merges = append(report.Merges, GroupMerge{NodeID: synthetic.ID, Members: ids})
+}
+
+// minPositiveTimeout returns the smallest positive Timeout across chain,
+// or 0 if none of its members set one. The whole chain now runs as a
+// single attempt, so the tightest bound any one member asked for is the
+// one that still has to hold.
+func minPositiveTimeout(chain []*TNode) time.Duration {
+	var min time.Duration
+	for _, n := range chain {
+		if n.Timeout <= 0 {
+			continue
+		}
+		if min == 0 || n.Timeout < min {
+			min = n.Timeout
+		}
+	}
+	return min
+}
+
+// maxRetries returns the Retries/Backoff of whichever chain member asked
+// for the most retries. Retrying the synthetic node re-runs every member
+// from the start, so it has to accommodate whichever member needed the
+// most resilience.
+func maxRetries(chain []*TNode) (retries int, backoff time.Duration) {
+	for _, n := range chain {
+		if n.Retries > retries {
+			retries = n.Retries
+			backoff = n.Backoff
+		}
+	}
+	return retries, backoff
+}
+
+// failurePolicyRank orders FailurePolicy from most to least permissive to
+// dependents, so strictestFailurePolicy can pick whichever member's
+// policy least loosens what the others asked for.
+func failurePolicyRank(p FailurePolicy) int {
+	switch p {
+	case ContinueOnFail:
+		return 0
+	case StopAll:
+		return 2
+	default: // StopDownstream
+		return 1
+	}
+}
+
+// strictestFailurePolicy returns whichever chain member's FailurePolicy
+// ranks least permissive to the chain's dependents, so folding a member
+// into a group never silently loosens the policy it asked for.
+func strictestFailurePolicy(chain []*TNode) FailurePolicy {
+	strictest := chain[0].FailurePolicy
+	for _, n := range chain[1:] {
+		if failurePolicyRank(n.FailurePolicy) > failurePolicyRank(strictest) {
+			strictest = n.FailurePolicy
+		}
+	}
+	return strictest
+}
+
+// GroupByPrefix groups directly-connected nodes whose IDs share every
+// segment up to the last occurrence of sep, e.g. "components.tags.create"
+// and "components.tags.get" merge under sep ".".
+func GroupByPrefix(sep string) GroupRule {
+	prefix := func(id string) string {
+		i := strings.LastIndex(id, sep)
+		if i < 0 {
+			return id
+		}
+		return id[:i]
+	}
+	return GroupRule{
+		Match: func(a, b *TNode) bool {
+			return prefix(a.ID) == prefix(b.ID)
+		},
+		Merge: sequentialMerge,
+	}
+}
+
+// GroupByTag groups directly-connected nodes that both carry the given
+// tag.
+func GroupByTag(tag string) GroupRule {
+	return GroupRule{
+		Match: func(a, b *TNode) bool {
+			return hasTag(a, tag) && hasTag(b, tag)
+		},
+		Merge: sequentialMerge,
+	}
+}
+
+func hasTag(n *TNode, tag string) bool {
+	for _, t := range n.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// sequentialMerge runs each member's Fn in chain order. Since the members
+// now run as one TNode, the TDag's BeforeEach/AfterEach fire once around
+// the whole chain instead of once per member.
+func sequentialMerge(group []*TNode) TestFn {
+	return func(ctx *TestContext) {
+		for _, n := range group {
+			if n.Fn != nil {
+				n.Fn(ctx)
+			}
+		}
+	}
+}
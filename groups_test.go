@@ -0,0 +1,166 @@
+package tdag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoGroupByPrefix(t *testing.T) {
+	order := []string{}
+	rec := func(id string) TestFn {
+		return func(ctx *TestContext) { order = append(order, id) }
+	}
+
+	d := NewTDag(t)
+	d.AddNode("setup", rec("setup"))
+	d.AddNode("components.tags.create", rec("create"))
+	d.AddNode("components.tags.get", rec("get"))
+	d.AddNode("components.tags.delete", rec("delete"))
+	d.AddNode("teardown", rec("teardown"))
+
+	if _, err := d.AddEdge("setup", "components.tags.create"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("components.tags.create", "components.tags.get"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("components.tags.get", "components.tags.delete"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("components.tags.delete", "teardown"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.AutoGroup(GroupByPrefix("."))
+	if len(report.Merges) != 1 {
+		t.Fatalf("expected 1 merge, got %d: %+v", len(report.Merges), report.Merges)
+	}
+	if len(d.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes after grouping, got %d", len(d.Nodes))
+	}
+
+	d.RunTests(t)
+
+	want := []string{"setup", "create", "get", "delete", "teardown"}
+	if len(order) != len(want) {
+		t.Fatalf("got order %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestAutoGroupRejectsBranching(t *testing.T) {
+	d := NewTDag(t)
+	d.AddNode("a.1", func(ctx *TestContext) {})
+	d.AddNode("a.2", func(ctx *TestContext) {})
+	d.AddNode("other", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("a.1", "a.2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("a.1", "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.AutoGroup(GroupByPrefix("."))
+	if len(report.Merges) != 0 {
+		t.Fatalf("expected no merge when head has a second external out-edge, got %+v", report.Merges)
+	}
+	if len(d.Nodes) != 3 {
+		t.Fatalf("expected nodes untouched, got %d", len(d.Nodes))
+	}
+}
+
+// TestAutoGroupIsOrderIndependent is a regression test: AddNode was
+// called in the non-topological order c, a, b, d even though the edges
+// form a single chain a->b->c->d. findGroupChain used to start a walk
+// from whichever node it reached first in d.Nodes, so this produced two
+// disjoint merges (c+d and a+b) instead of the one maximal chain the
+// feature promises.
+func TestAutoGroupIsOrderIndependent(t *testing.T) {
+	d := NewTDag(t)
+	d.AddNode("g.c", func(ctx *TestContext) {})
+	d.AddNode("g.a", func(ctx *TestContext) {})
+	d.AddNode("g.b", func(ctx *TestContext) {})
+	d.AddNode("g.d", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("g.a", "g.b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("g.b", "g.c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("g.c", "g.d"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.AutoGroup(GroupByPrefix("."))
+	if len(report.Merges) != 1 {
+		t.Fatalf("expected a single maximal merge regardless of AddNode order, got %d: %+v", len(report.Merges), report.Merges)
+	}
+	if len(report.Merges[0].Members) != 4 {
+		t.Fatalf("expected all 4 nodes in one merge, got %+v", report.Merges[0])
+	}
+}
+
+func TestAutoGroupByTag(t *testing.T) {
+	d := NewTDag(t)
+	d.AddNodeOpts("x", func(ctx *TestContext) {}, WithTags("fixture"))
+	d.AddNodeOpts("y", func(ctx *TestContext) {}, WithTags("fixture"))
+	d.AddNode("z", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("y", "z"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.AutoGroup(GroupByTag("fixture"))
+	if len(report.Merges) != 1 {
+		t.Fatalf("expected 1 merge, got %d: %+v", len(report.Merges), report.Merges)
+	}
+	if len(d.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes after grouping, got %d", len(d.Nodes))
+	}
+}
+
+// TestAutoGroupMergesMemberPolicy is a regression test: mergeChain used
+// to copy Timeout/Retries/Backoff/FailurePolicy from chain[0] only, so a
+// non-head member's own policy was silently dropped once it was folded
+// into a group. The synthetic node should instead reflect whichever
+// member asked for the tightest timeout, the most retries, and the
+// least permissive failure policy.
+func TestAutoGroupMergesMemberPolicy(t *testing.T) {
+	d := NewTDag(t)
+	d.AddNodeOpts("g.a", func(ctx *TestContext) {}, WithTimeout(time.Hour))
+	d.AddNodeOpts("g.b", func(ctx *TestContext) {}, WithRetries(3, time.Millisecond))
+	d.AddNodeOpts("g.c", func(ctx *TestContext) {},
+		WithTimeout(time.Millisecond), WithFailurePolicy(StopAll))
+
+	if _, err := d.AddEdge("g.a", "g.b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("g.b", "g.c"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := d.AutoGroup(GroupByPrefix("."))
+	if len(report.Merges) != 1 {
+		t.Fatalf("expected 1 merge, got %d: %+v", len(report.Merges), report.Merges)
+	}
+
+	synthetic := d.Nodes[0]
+	if synthetic.Timeout != time.Millisecond {
+		t.Fatalf("expected the tightest member timeout to win, got %v", synthetic.Timeout)
+	}
+	if synthetic.Retries != 3 {
+		t.Fatalf("expected the member with the most retries to win, got %d", synthetic.Retries)
+	}
+	if synthetic.FailurePolicy != StopAll {
+		t.Fatalf("expected the least permissive failure policy to win, got %v", synthetic.FailurePolicy)
+	}
+}
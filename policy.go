@@ -0,0 +1,93 @@
+package tdag
+
+import "time"
+
+// FailurePolicy controls what happens to a node's dependents when the
+// node itself fails. The zero value is StopDownstream.
+type FailurePolicy int
+
+const (
+	// StopDownstream skips the node's dependents (and, transitively,
+	// theirs) when the node fails, unless the edge to them has SkipOK set.
+	StopDownstream FailurePolicy = iota
+	// ContinueOnFail lets dependents run normally even though the node
+	// failed.
+	ContinueOnFail
+	// StopAll aborts the entire run: no further nodes are scheduled.
+	StopAll
+)
+
+// NodeOption configures a node added via AddNodeOpts.
+type NodeOption func(*TNode)
+
+// WithTimeout bounds how long a single attempt of the node's Fn may run.
+// A timed-out attempt is treated as a failed attempt, reported as soon as
+// the deadline passes. Fn should select on ctx.Ctx.Done() to actually
+// stop work at that point; if it doesn't, the attempt still isn't
+// abandoned until Fn returns, since testing.T cannot safely be touched
+// from a goroutine once its subtest has completed.
+func WithTimeout(d time.Duration) NodeOption {
+	return func(n *TNode) {
+		n.Timeout = d
+	}
+}
+
+// WithRetries retries a failing node up to n additional times, waiting
+// backoff before the first retry and doubling the wait after each
+// subsequent one.
+func WithRetries(n int, backoff time.Duration) NodeOption {
+	return func(node *TNode) {
+		node.Retries = n
+		node.Backoff = backoff
+	}
+}
+
+// WithFailurePolicy controls whether a node's dependents run after it
+// fails. See FailurePolicy.
+func WithFailurePolicy(p FailurePolicy) NodeOption {
+	return func(n *TNode) {
+		n.FailurePolicy = p
+	}
+}
+
+// WithMaxParallel caps how many nodes may run concurrently across the
+// whole TDag, not just the ones carrying this option. The first
+// AddNodeOpts call that sets a positive value wins.
+func WithMaxParallel(n int) NodeOption {
+	return func(node *TNode) {
+		node.MaxParallel = n
+	}
+}
+
+// WithTags attaches free-form tags to a node, e.g. for transformers or
+// groupers to match on.
+func WithTags(tags ...string) NodeOption {
+	return func(n *TNode) {
+		n.Tags = append(n.Tags, tags...)
+	}
+}
+
+// WithFnVersion sets a node's FnVersion, used to bust its result cache
+// (see WithCacheDir) when Fn's body changed without its identity
+// changing, e.g. an anonymous closure.
+func WithFnVersion(version string) NodeOption {
+	return func(n *TNode) {
+		n.FnVersion = version
+	}
+}
+
+// RunOption configures a Run invocation.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	concurrency int
+}
+
+// WithConcurrency caps the number of nodes Run executes concurrently.
+// Zero (the default) leaves the worker pool unbounded, spawning one
+// goroutine per ready node as RunTests and RunTo do.
+func WithConcurrency(n int) RunOption {
+	return func(c *runConfig) {
+		c.concurrency = n
+	}
+}
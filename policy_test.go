@@ -0,0 +1,206 @@
+package tdag
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scenarioEnvVar gates the *Scenario tests below so a plain `go test ./...`
+// run skips them: each one genuinely fails an attempt via ctx.T.Fatal or
+// ctx.T.Errorf to exercise retries, failure policies, or timeouts, and a
+// failing subtest unconditionally marks every ancestor test failed with no
+// way to swallow that in-process. Their driver test (the non-Scenario
+// counterpart below) re-invokes the test binary with this var set and the
+// run restricted to just the scenario, then asserts on its logged RESULT
+// line — the same self-exec pattern the standard library uses to test code
+// that calls Fatal or os.Exit.
+const scenarioEnvVar = "TDAG_RUN_SCENARIO"
+
+func skipUnlessScenario(t *testing.T) {
+	t.Helper()
+	if os.Getenv(scenarioEnvVar) != "1" {
+		t.Skip("only runs as a subprocess of its driver test")
+	}
+}
+
+// runScenario re-invokes the current test binary with -test.run restricted
+// to name and scenarioEnvVar set, returning its combined output. The
+// subprocess is expected to fail; callers assert on the RESULT line it
+// logs, not its exit status.
+func runScenario(t *testing.T, name string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$", "-test.v")
+	cmd.Env = append(os.Environ(), scenarioEnvVar+"=1")
+	out, _ := cmd.CombinedOutput()
+	return string(out)
+}
+
+func TestRetriesEventuallySucceedScenario(t *testing.T) {
+	skipUnlessScenario(t)
+
+	var attempts int32
+	d := NewTDag(t)
+	d.AddNodeOpts("flaky", func(ctx *TestContext) {
+		n := atomic.AddInt32(&attempts, 1)
+		if n < 3 {
+			ctx.T.Fatal("not yet")
+		}
+	}, WithRetries(2, time.Millisecond))
+
+	d.RunTests(t)
+
+	passed, ran := d.nodePassed("flaky")
+	t.Logf("RESULT attempts=%d ran=%v passed=%v", attempts, ran, passed)
+}
+
+func TestRetriesEventuallySucceed(t *testing.T) {
+	out := runScenario(t, "TestRetriesEventuallySucceedScenario")
+	if !strings.Contains(out, "RESULT attempts=3 ran=true passed=true") {
+		t.Fatalf("scenario did not report the expected outcome:\n%s", out)
+	}
+}
+
+func TestFailurePolicyStopDownstreamSkipsDependentsScenario(t *testing.T) {
+	skipUnlessScenario(t)
+
+	var ranB int32
+	d := NewTDag(t)
+	d.AddNode("a", func(ctx *TestContext) { ctx.T.Fatal("boom") })
+	d.AddNode("b", func(ctx *TestContext) { atomic.AddInt32(&ranB, 1) })
+	if _, err := d.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	d.RunTests(t)
+
+	status, ran := d.nodeStatus("b")
+	t.Logf("RESULT ranB=%d status=%d ran=%v", ranB, int(status), ran)
+}
+
+func TestFailurePolicyStopDownstreamSkipsDependents(t *testing.T) {
+	out := runScenario(t, "TestFailurePolicyStopDownstreamSkipsDependentsScenario")
+	want := fmt.Sprintf("RESULT ranB=0 status=%d ran=true", int(statusSkipped))
+	if !strings.Contains(out, want) {
+		t.Fatalf("scenario did not report the expected outcome:\n%s", out)
+	}
+}
+
+func TestFailurePolicyContinueOnFailRunsDependentsScenario(t *testing.T) {
+	skipUnlessScenario(t)
+
+	var ranB int32
+	d := NewTDag(t)
+	d.AddNodeOpts("a", func(ctx *TestContext) { ctx.T.Fatal("boom") }, WithFailurePolicy(ContinueOnFail))
+	d.AddNode("b", func(ctx *TestContext) { atomic.AddInt32(&ranB, 1) })
+	if _, err := d.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	d.RunTests(t)
+
+	t.Logf("RESULT ranB=%d", ranB)
+}
+
+func TestFailurePolicyContinueOnFailRunsDependents(t *testing.T) {
+	out := runScenario(t, "TestFailurePolicyContinueOnFailRunsDependentsScenario")
+	if !strings.Contains(out, "RESULT ranB=1") {
+		t.Fatalf("scenario did not report b running despite a failing with ContinueOnFail:\n%s", out)
+	}
+}
+
+func TestTimeoutFailsAttemptScenario(t *testing.T) {
+	skipUnlessScenario(t)
+
+	d := NewTDag(t)
+	d.AddNodeOpts("slow", func(ctx *TestContext) {
+		<-ctx.Ctx.Done()
+	}, WithTimeout(10*time.Millisecond))
+
+	d.RunTests(t)
+
+	status, ran := d.nodeStatus("slow")
+	t.Logf("RESULT status=%d ran=%v", int(status), ran)
+}
+
+func TestTimeoutFailsAttempt(t *testing.T) {
+	out := runScenario(t, "TestTimeoutFailsAttemptScenario")
+	want := fmt.Sprintf("RESULT status=%d ran=true", int(statusFailed))
+	if !strings.Contains(out, want) {
+		t.Fatalf("scenario did not report the expected outcome:\n%s", out)
+	}
+}
+
+// TestTimeoutDoesNotPanicOnLateTUsageScenario is a regression test for a
+// node whose Fn ignores ctx.Ctx.Done() and keeps running past the deadline,
+// then touches ctx.T. Before runAttempt waited out the goroutine after a
+// timeout, this crashed the whole test binary with "Fail in goroutine after
+// ... has completed" instead of just failing the attempt — which is exactly
+// why this has to run as its own subprocess: a crash needs to be observable
+// without taking the rest of the suite down with it.
+func TestTimeoutDoesNotPanicOnLateTUsageScenario(t *testing.T) {
+	skipUnlessScenario(t)
+
+	d := NewTDag(t)
+	d.AddNodeOpts("late", func(ctx *TestContext) {
+		time.Sleep(30 * time.Millisecond)
+		ctx.T.Errorf("tried to use t after the deadline")
+	}, WithTimeout(5*time.Millisecond))
+
+	d.RunTests(t)
+
+	status, ran := d.nodeStatus("late")
+	t.Logf("RESULT status=%d ran=%v", int(status), ran)
+}
+
+func TestTimeoutDoesNotPanicOnLateTUsage(t *testing.T) {
+	out := runScenario(t, "TestTimeoutDoesNotPanicOnLateTUsageScenario")
+	if strings.Contains(out, "panic:") {
+		t.Fatalf("scenario panicked instead of just failing the attempt:\n%s", out)
+	}
+	want := fmt.Sprintf("RESULT status=%d ran=true", int(statusFailed))
+	if !strings.Contains(out, want) {
+		t.Fatalf("scenario did not report the expected outcome:\n%s", out)
+	}
+}
+
+// TestWithMaxParallelBoundsConcurrency gives the dag a batch of
+// independent nodes (no edges between them, so all become available at
+// once) and checks that MaxParallel actually caps how many attempts run
+// at the same time. MaxParallel is dag-wide once any single node
+// requests it, not scoped to just that node, so only one of the ten
+// nodes below sets WithMaxParallel.
+func TestWithMaxParallelBoundsConcurrency(t *testing.T) {
+	d := NewTDag(t)
+
+	var current, max int32
+	track := func(ctx *TestContext) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+	}
+
+	d.AddNodeOpts("n0", track, WithMaxParallel(2))
+	for i := 1; i < 10; i++ {
+		d.AddNode(fmt.Sprintf("n%d", i), track)
+	}
+
+	d.RunTests(t)
+
+	if max > 2 {
+		t.Fatalf("expected at most 2 nodes running at once, saw %d", max)
+	}
+	if max < 2 {
+		t.Fatalf("expected concurrency to actually reach the cap of 2, saw %d", max)
+	}
+}
@@ -5,25 +5,122 @@ import (
 	"sync"
 )
 
+// TStore is the key/value store shared by a TDag's nodes via
+// TestContext.Store. A child obtained from Scope namespaces writes to
+// itself while still reading through to its parent, so one node's
+// fixtures don't leak into another's but producers and consumers further
+// up the chain can still share state.
 type TStore struct {
-	items map[string]interface{}
-	mu    sync.Mutex
+	items    map[string]interface{}
+	mu       sync.Mutex
+	parent   *TStore
+	scopeID  string
+	watchers map[string][]chan any
 }
 
+// Set stores value under key.
+//
+// Deprecated: use the generic Set instead; it avoids the interface{}
+// cast callers otherwise need on the way back out.
 func (s *TStore) Set(key string, value interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.items[key] = value
+	s.notify(key, value)
 }
 
+// Get retrieves the value stored under key, reading through to a parent
+// Scope if key was never set locally.
+//
+// Deprecated: use the generic Get instead; it avoids the interface{}
+// cast callers otherwise need on the way back out.
 func (s *TStore) Get(key string) (interface{}, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	value, ok := s.items[key]
-	if !ok {
-		return nil, fmt.Errorf("key %s not found", key)
+	s.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	if s.parent != nil {
+		return s.parent.Get(key)
+	}
+	if s.scopeID != "" {
+		return nil, fmt.Errorf("key %s not found in scope %s", key, s.scopeID)
+	}
+	return nil, fmt.Errorf("key %s not found", key)
+}
+
+// Delete removes key from the store. It does not reach into a parent
+// Scope.
+func (s *TStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
+
+// Keys returns every key set directly on this store, excluding any held
+// only by a parent Scope.
+func (s *TStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.items))
+	for k := range s.items {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Snapshot returns a copy of every key/value pair set directly on this
+// store, excluding any held only by a parent Scope.
+func (s *TStore) Snapshot() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]any, len(s.items))
+	for k, v := range s.items {
+		out[k] = v
+	}
+	return out
+}
+
+// Scope returns a child store for the given node: its Set calls land in
+// the child, keeping them out of the parent's Snapshot/Keys, while its
+// Get calls fall through to the parent for keys the node hasn't written
+// itself.
+func (s *TStore) Scope(nodeID string) *TStore {
+	return &TStore{
+		items:   make(map[string]interface{}),
+		parent:  s,
+		scopeID: nodeID,
 	}
-	return value, nil
+}
+
+// Watch returns a channel that receives the value written to key, either
+// immediately if it is already set or as soon as a later Set delivers it.
+// It lets a downstream node block on an upstream write instead of
+// polling Get. The channel receives at most one value and is never
+// closed.
+func (s *TStore) Watch(key string) <-chan any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan any, 1)
+	if v, ok := s.items[key]; ok {
+		ch <- v
+		return ch
+	}
+	if s.watchers == nil {
+		s.watchers = make(map[string][]chan any)
+	}
+	s.watchers[key] = append(s.watchers[key], ch)
+	return ch
+}
+
+// notify delivers value to every channel Watch handed out for key. Called
+// with mu held.
+func (s *TStore) notify(key string, value any) {
+	for _, ch := range s.watchers[key] {
+		ch <- value
+	}
+	delete(s.watchers, key)
 }
 
 func NewStore() *TStore {
@@ -32,3 +129,44 @@ func NewStore() *TStore {
 		mu:    sync.Mutex{},
 	}
 }
+
+// Get retrieves a typed value for key from store, reading through to any
+// parent Scope if the key isn't set locally.
+func Get[T any](store *TStore, key string) (T, error) {
+	var zero T
+	v, err := store.Get(key)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("key %s holds %T, not %T", key, v, zero)
+	}
+	return typed, nil
+}
+
+// Set stores a typed value for key.
+func Set[T any](store *TStore, key string, value T) {
+	store.Set(key, value)
+}
+
+// MustGet is like Get but panics if key is absent or holds the wrong
+// type. Use it in setup code where a missing dependency is a programming
+// error, not a recoverable condition.
+func MustGet[T any](store *TStore, key string) T {
+	v, err := Get[T](store, key)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// GetOr is like Get but returns def instead of an error when key is
+// absent or holds the wrong type.
+func GetOr[T any](store *TStore, key string, def T) T {
+	v, err := Get[T](store, key)
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,88 @@
+package tdag
+
+import "testing"
+
+func TestGenericStoreGetSet(t *testing.T) {
+	s := NewStore()
+	Set(s, "count", 42)
+	Set(s, "name", "hi")
+
+	got, err := Get[int](s, "count")
+	if err != nil || got != 42 {
+		t.Fatalf("Get[int] = %v, %v", got, err)
+	}
+
+	if _, err := Get[string](s, "count"); err == nil {
+		t.Fatal("expected type mismatch error")
+	}
+
+	if v := GetOr(s, "missing", 7); v != 7 {
+		t.Fatalf("GetOr = %d, want 7", v)
+	}
+
+	if v := MustGet[string](s, "name"); v != "hi" {
+		t.Fatalf("MustGet = %q", v)
+	}
+}
+
+func TestStoreDeleteAndKeys(t *testing.T) {
+	s := NewStore()
+	Set(s, "a", 1)
+	Set(s, "b", 2)
+
+	if len(s.Keys()) != 2 {
+		t.Fatalf("expected 2 keys, got %v", s.Keys())
+	}
+
+	s.Delete("a")
+	if len(s.Keys()) != 1 {
+		t.Fatalf("expected 1 key after delete, got %v", s.Keys())
+	}
+	if _, err := s.Get("a"); err == nil {
+		t.Fatal("expected deleted key to be absent")
+	}
+}
+
+func TestStoreScopeReadsThroughToParent(t *testing.T) {
+	parent := NewStore()
+	parent.Set("shared", "parent-value")
+
+	child := parent.Scope("node-a")
+	child.Set("local", "child-value")
+
+	if v, err := child.Get("shared"); err != nil || v != "parent-value" {
+		t.Fatalf("expected scope to read through to parent, got %v, %v", v, err)
+	}
+	if _, err := parent.Get("local"); err == nil {
+		t.Fatal("expected parent not to see child-only key")
+	}
+	if len(parent.Keys()) != 1 {
+		t.Fatalf("expected parent keys untouched by child writes, got %v", parent.Keys())
+	}
+}
+
+func TestStoreWatchUnblocksOnSet(t *testing.T) {
+	s := NewStore()
+	ch := s.Watch("ready")
+
+	done := make(chan struct{})
+	go func() {
+		s.Set("ready", true)
+		close(done)
+	}()
+
+	if v := <-ch; v != true {
+		t.Fatalf("watch delivered %v, want true", v)
+	}
+	<-done
+}
+
+func TestStoreWatchDeliversAlreadySetValue(t *testing.T) {
+	s := NewStore()
+	s.Set("ready", "go")
+
+	ch := s.Watch("ready")
+	if v := <-ch; v != "go" {
+		t.Fatalf("watch delivered %v, want %q", v, "go")
+	}
+}
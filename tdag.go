@@ -1,11 +1,13 @@
 package tdag
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 type TDag struct {
@@ -16,30 +18,113 @@ type TDag struct {
 	TearDownFns   []func(ctx *TestContext)
 	BeforeEachFns []func(ctx *TestContext)
 	AfterEachFns  []func(ctx *TestContext)
+
+	statusMu sync.Mutex
+	status   map[string]nodeStatus
+
+	abortMu     sync.Mutex
+	aborted     bool
+	abortReason string
+
+	// parallelSem, when set by AddNodeOpts' WithMaxParallel, caps how many
+	// nodes may run concurrently across the whole TDag regardless of
+	// which node carries the option.
+	parallelSem chan struct{}
+
+	// cacheDir is where node result manifests are persisted. See
+	// WithCacheDir; empty disables caching.
+	cacheDir string
 }
 
+// TNode is a single unit of work in the graph. Retries, Backoff, Timeout,
+// FailurePolicy, MaxParallel, and Tags are set via AddNodeOpts and its
+// NodeOptions; nodes added with AddNode run once, with no timeout, and
+// stop their dependents on failure.
 type TNode struct {
-	ID string
-	Fn TestFn
+	ID            string
+	Fn            TestFn
+	Timeout       time.Duration
+	Retries       int
+	Backoff       time.Duration
+	FailurePolicy FailurePolicy
+	MaxParallel   int
+	Tags          []string
+
+	// FnVersion disambiguates a node's Fn for caching purposes when its
+	// runtime identity alone (an anonymous closure, or a function whose
+	// body changed without being renamed) isn't enough. Set via
+	// WithFnVersion.
+	FnVersion string
 }
 
+// TEdge connects two nodes. Constraints gate whether Right may run once it
+// becomes available, and SkipOK controls whether Right still runs when
+// Left was itself skipped or failed.
 type TEdge struct {
-	Left  *TNode
-	Right *TNode
+	Left        *TNode
+	Right       *TNode
+	Meta        map[string]any
+	Constraints []Constraint
+	SkipOK      bool
 }
 
 type TestContext struct {
 	Store *TStore
 	T     *testing.T
+	Ctx   context.Context
+
+	dag *TDag
+
+	// ifchange and ifcreate collect this attempt's Ifchange/Ifcreate
+	// declarations so the cache manifest can be built once Fn returns.
+	ifchange []string
+	ifcreate []string
+}
+
+// Ifchange declares that the node depends on the current contents of the
+// given files: if any of them hashes differently on a later run, the
+// node's cache entry (see WithCacheDir) is invalidated and Fn runs again.
+func (c *TestContext) Ifchange(paths ...string) {
+	c.ifchange = append(c.ifchange, paths...)
+}
+
+// Ifcreate declares that the node depends on the given files' absence: if
+// any of them exists on a later run, the node's cache entry is
+// invalidated and Fn runs again.
+func (c *TestContext) Ifcreate(paths ...string) {
+	c.ifcreate = append(c.ifcreate, paths...)
 }
 
 type TestFn func(ctx *TestContext)
 
-func NewTDag(t *testing.T) *TDag {
-	return &TDag{
+// nodeStatus records the outcome of a node once it has run, or the
+// decision made not to run it.
+type nodeStatus int
+
+const (
+	statusPassed nodeStatus = iota
+	statusFailed
+	statusSkipped
+)
+
+// TDagOption configures a TDag at construction time. See WithCacheDir.
+type TDagOption func(*TDag)
+
+// WithCacheDir opts a TDag into result caching, persisting node manifests
+// under path (".tdag-cache" is the conventional choice). Without it,
+// cacheDir stays "" and every node always runs. Pass "" to opt back out.
+func WithCacheDir(path string) TDagOption {
+	return func(d *TDag) {
+		d.cacheDir = path
+	}
+}
+
+func NewTDag(t *testing.T, opts ...TDagOption) *TDag {
+	d := &TDag{
 		Ctx: &TestContext{
 			Store: NewStore(),
 			T:     t,
+			Ctx:   context.Background(),
 		},
 		Nodes:         []*TNode{},
 		Edges:         []*TEdge{},
@@ -47,7 +132,13 @@ func NewTDag(t *testing.T) *TDag {
 		TearDownFns:   []func(ctx *TestContext){},
 		BeforeEachFns: []func(ctx *TestContext){},
 		AfterEachFns:  []func(ctx *TestContext){},
+		status:        make(map[string]nodeStatus),
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
+	d.Ctx.dag = d
+	return d
 }
 
 func (d *TDag) AddNode(id string, fn TestFn) *TNode {
@@ -59,6 +150,24 @@ func (d *TDag) AddNode(id string, fn TestFn) *TNode {
 	return node
 }
 
+// AddNodeOpts adds a node with per-node policy: timeouts, retries,
+// failure isolation, a dag-wide parallelism cap, and tags. See
+// WithTimeout, WithRetries, WithFailurePolicy, WithMaxParallel, and
+// WithTags.
+func (d *TDag) AddNodeOpts(id string, fn TestFn, opts ...NodeOption) *TNode {
+	node := &TNode{ID: id, Fn: fn}
+	for _, opt := range opts {
+		opt(node)
+	}
+	d.Nodes = append(d.Nodes, node)
+
+	if node.MaxParallel > 0 && d.parallelSem == nil {
+		d.parallelSem = make(chan struct{}, node.MaxParallel)
+	}
+
+	return node
+}
+
 func (d *TDag) AddEdge(from string, to ...string) ([]*TEdge, error) {
 	var edges []*TEdge
 	fromNode := d.findNodeByID(from)
@@ -86,6 +195,21 @@ func (d *TDag) AddEdge(from string, to ...string) ([]*TEdge, error) {
 	return edges, nil
 }
 
+// AddEdgeWith adds a single edge from from to to, gated by the given
+// constraints. The target only runs once every constraint is satisfied;
+// otherwise it is skipped, and its dependents are skipped in turn unless
+// the target's FailurePolicy is ContinueOnFail or the edge from the
+// target to a dependent has SkipOK set.
+func (d *TDag) AddEdgeWith(from, to string, constraints ...Constraint) (*TEdge, error) {
+	edges, err := d.AddEdge(from, to)
+	if err != nil {
+		return nil, err
+	}
+	edge := edges[0]
+	edge.Constraints = constraints
+	return edge, nil
+}
+
 func (d *TDag) Setup(fn func(ctx *TestContext)) {
 	d.SetupFns = append(d.SetupFns, fn)
 }
@@ -116,81 +240,7 @@ func (d *TDag) findNodeByID(id string) *TNode {
 // Arguments:
 //   - t: The testing object.
 func (d *TDag) RunTests(t *testing.T) {
-	// Create dependency graph and track in-degree for each node.
-	inDegree := make(map[string]int)
-	outEdges := make(map[string][]*TNode)
-
-	// Initialize in-degree counts and build adjacency list.
-	for _, node := range d.Nodes {
-		inDegree[node.ID] = 0
-	}
-
-	// Build adjacency list.
-	for _, edge := range d.Edges {
-		inDegree[edge.Right.ID]++
-		outEdges[edge.Left.ID] = append(outEdges[edge.Left.ID], edge.Right)
-	}
-
-	// Keep track of completed nodes.
-	completed := make(map[string]bool)
-	var completedMux sync.Mutex
-
-	// Run tests in topological order.
-	for {
-		// Find nodes with no dependencies.
-		var available []*TNode
-		for _, node := range d.Nodes {
-			completedMux.Lock()
-			if !completed[node.ID] && inDegree[node.ID] == 0 {
-				available = append(available, node)
-			}
-			completedMux.Unlock()
-		}
-
-		// If no nodes are available but we haven't processed all nodes, we have a cycle.
-		if len(available) == 0 {
-			var remaining []string
-			completedMux.Lock()
-			for _, node := range d.Nodes {
-				if !completed[node.ID] {
-					remaining = append(remaining, node.ID)
-				}
-			}
-			completedMux.Unlock()
-
-			if len(remaining) > 0 {
-				t.Fatalf("Dependency cycle detected. Remaining nodes: %v", remaining)
-			}
-			break
-		}
-
-		// Run available tests
-		var wg sync.WaitGroup
-		for _, node := range available {
-			wg.Add(1)
-			go func(n *TNode) {
-				defer wg.Done()
-				t.Run(n.ID, func(t *testing.T) {
-					// Run the test passed in.
-					for _, fn := range d.BeforeEachFns {
-						fn(d.Ctx)
-					}
-					n.Fn(d.Ctx)
-					for _, fn := range d.AfterEachFns {
-						fn(d.Ctx)
-					}
-					// Mark as completed and update dependencies.
-					completedMux.Lock()
-					completed[n.ID] = true
-					for _, dependent := range outEdges[n.ID] {
-						inDegree[dependent.ID]--
-					}
-					completedMux.Unlock()
-				})
-			}(node)
-		}
-		wg.Wait()
-	}
+	d.schedule(t, d.Nodes, d.Edges, nil)
 
 	// Run teardown functions.
 	for _, fn := range d.TearDownFns {
@@ -215,43 +265,89 @@ func (d *TDag) RunTo(id string, t *testing.T) {
 	requiredNodes := make(map[string]bool)
 	d.collectDependencies(id, requiredNodes)
 
-	// Create dependency graph and track in-degree for required nodes
-	inDegree := make(map[string]int)
-	outEdges := make(map[string][]*TNode)
-
-	// Initialize in-degree counts only for required nodes.
+	var nodes []*TNode
 	for _, node := range d.Nodes {
 		if requiredNodes[node.ID] {
-			inDegree[node.ID] = 0
+			nodes = append(nodes, node)
 		}
 	}
 
-	// Build adjacency list only for required nodes.
+	var edges []*TEdge
 	for _, edge := range d.Edges {
 		if requiredNodes[edge.Left.ID] && requiredNodes[edge.Right.ID] {
-			inDegree[edge.Right.ID]++
-			outEdges[edge.Left.ID] = append(outEdges[edge.Left.ID], edge.Right)
+			edges = append(edges, edge)
 		}
 	}
 
-	// Keep track of completed nodes
-	completed := make(map[string]bool)
-	var completedMux sync.Mutex
+	// Run setup functions.
+	for _, fn := range d.SetupFns {
+		fn(d.Ctx)
+	}
+
+	d.schedule(t, nodes, edges, nil)
+
+	// Run teardown functions.
+	for _, fn := range d.TearDownFns {
+		fn(d.Ctx)
+	}
+}
+
+// Run runs the tests in topological order like RunTests, but accepts
+// RunOptions. WithConcurrency caps the number of nodes run concurrently
+// instead of spawning one goroutine per ready node.
+func (d *TDag) Run(t *testing.T, opts ...RunOption) {
+	cfg := &runConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var sem chan struct{}
+	if cfg.concurrency > 0 {
+		sem = make(chan struct{}, cfg.concurrency)
+	}
 
 	// Run setup functions.
 	for _, fn := range d.SetupFns {
 		fn(d.Ctx)
 	}
 
-	// Run tests in topological order.
+	d.schedule(t, d.Nodes, d.Edges, sem)
+
+	// Run teardown functions.
+	for _, fn := range d.TearDownFns {
+		fn(d.Ctx)
+	}
+}
+
+// schedule runs nodes in topological order over the given edge set. sem,
+// if non-nil, bounds how many nodes run concurrently; nil spawns one
+// goroutine per ready node.
+func (d *TDag) schedule(t *testing.T, nodes []*TNode, edges []*TEdge, sem chan struct{}) {
+	inDegree := make(map[string]int)
+	outEdges := make(map[string][]*TNode)
+	inEdges := make(map[string][]*TEdge)
+
+	for _, node := range nodes {
+		inDegree[node.ID] = 0
+	}
+	for _, edge := range edges {
+		inDegree[edge.Right.ID]++
+		outEdges[edge.Left.ID] = append(outEdges[edge.Left.ID], edge.Right)
+		inEdges[edge.Right.ID] = append(inEdges[edge.Right.ID], edge)
+	}
+
+	completed := make(map[string]bool)
+	var completedMux sync.Mutex
+
 	for {
+		if aborted, reason := d.isAborted(); aborted {
+			t.Fatalf("tdag: run aborted: %s", reason)
+			return
+		}
+
 		// Find nodes with no dependencies.
 		var available []*TNode
-		for _, node := range d.Nodes {
-			// Only consider nodes that are required.
-			if !requiredNodes[node.ID] {
-				continue
-			}
+		for _, node := range nodes {
 			completedMux.Lock()
 			if !completed[node.ID] && inDegree[node.ID] == 0 {
 				available = append(available, node)
@@ -259,13 +355,13 @@ func (d *TDag) RunTo(id string, t *testing.T) {
 			completedMux.Unlock()
 		}
 
-		// If no nodes are available but we haven't processed all required nodes, we have a cycle.
+		// If no nodes are available but we haven't processed all nodes, we have a cycle.
 		if len(available) == 0 {
 			var remaining []string
 			completedMux.Lock()
-			for nodeID := range requiredNodes {
-				if !completed[nodeID] {
-					remaining = append(remaining, nodeID)
+			for _, node := range nodes {
+				if !completed[node.ID] {
+					remaining = append(remaining, node.ID)
 				}
 			}
 			completedMux.Unlock()
@@ -279,33 +375,152 @@ func (d *TDag) RunTo(id string, t *testing.T) {
 		// Run available tests.
 		var wg sync.WaitGroup
 		for _, node := range available {
+			if sem != nil {
+				sem <- struct{}{}
+			}
 			wg.Add(1)
 			go func(n *TNode) {
 				defer wg.Done()
-				t.Run(n.ID, func(t *testing.T) {
-					for _, fn := range d.BeforeEachFns {
-						fn(d.Ctx)
-					}
-					n.Fn(d.Ctx)
-					for _, fn := range d.AfterEachFns {
-						fn(d.Ctx)
-					}
-					completedMux.Lock()
-					completed[n.ID] = true
-					for _, dependent := range outEdges[n.ID] {
-						inDegree[dependent.ID]--
-					}
-					completedMux.Unlock()
-				})
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				d.runNode(t, n, inEdges)
+				completedMux.Lock()
+				completed[n.ID] = true
+				for _, dependent := range outEdges[n.ID] {
+					inDegree[dependent.ID]--
+				}
+				completedMux.Unlock()
 			}(node)
 		}
 		wg.Wait()
 	}
+}
 
-	// Run teardown functions.
-	for _, fn := range d.TearDownFns {
-		fn(d.Ctx)
+// runNode runs a single node as a subtest, honoring BeforeEach/AfterEach,
+// the node's retries and timeout, and skipping the node if blockedBy
+// reports it should not run.
+func (d *TDag) runNode(t *testing.T, n *TNode, inEdges map[string][]*TEdge) {
+	t.Run(n.ID, func(t *testing.T) {
+		if blocked, msg := d.blockedBy(n.ID, inEdges); blocked {
+			d.setStatus(n.ID, statusSkipped)
+			t.Skip(msg)
+			return
+		}
+
+		// Hash the store once, before Fn runs, and reuse it for both the
+		// cache check and (on a pass) the saved manifest - hashing it again
+		// after Fn returns would include the node's own writes and could
+		// never match a prior hit again.
+		preStoreHash := d.Ctx.Store.snapshotHash()
+
+		if d.cacheEnabled() {
+			if m, ok := d.loadManifest(n.ID); ok && cacheHit(m, n, preStoreHash) {
+				t.Logf("tdag: cache hit %s", n.ID)
+				d.setStatus(n.ID, statusPassed)
+				return
+			}
+		}
+
+		// Each attempt gets its own subtest so a later attempt passing is
+		// detectable: testing.T's failure state is permanent and would
+		// otherwise make every attempt after the first failure look
+		// failed too.
+		backoff := n.Backoff
+		passed := false
+		var runCtx *TestContext
+		for attempt := 0; attempt <= n.Retries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+				t.Logf("tdag: retrying %s (attempt %d/%d)", n.ID, attempt+1, n.Retries+1)
+			}
+
+			label := "attempt"
+			if n.Retries > 0 {
+				label = fmt.Sprintf("attempt-%d", attempt+1)
+			}
+			passed = t.Run(label, func(t *testing.T) {
+				runCtx = d.runAttempt(t, n)
+			})
+			if passed {
+				break
+			}
+		}
+
+		if !passed {
+			d.setStatus(n.ID, statusFailed)
+			if n.FailurePolicy == StopAll {
+				d.abort(fmt.Sprintf("node %s failed with StopAll failure policy", n.ID))
+			}
+			return
+		}
+		d.setStatus(n.ID, statusPassed)
+
+		if d.cacheEnabled() {
+			if err := d.saveManifest(n, runCtx, preStoreHash); err != nil {
+				t.Logf("tdag: failed to cache result for %s: %v", n.ID, err)
+			}
+		}
+	})
+}
+
+// runAttempt runs a single attempt of a node's Fn, honoring its Timeout
+// and the TDag's MaxParallel cap. It returns the TestContext the attempt
+// ran with, so the caller can inspect any Ifchange/Ifcreate declarations
+// once it passes.
+func (d *TDag) runAttempt(t *testing.T, n *TNode) *TestContext {
+	if d.parallelSem != nil {
+		d.parallelSem <- struct{}{}
+		defer func() { <-d.parallelSem }()
+	}
+
+	runCtx := *d.Ctx
+	runCtx.T = t
+	var ctx context.Context = context.Background()
+	var cancel context.CancelFunc
+	if n.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+	runCtx.Ctx = ctx
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, fn := range d.BeforeEachFns {
+			fn(&runCtx)
+		}
+		n.Fn(&runCtx)
+		for _, fn := range d.AfterEachFns {
+			fn(&runCtx)
+		}
+	}()
+
+	if n.Timeout > 0 {
+		select {
+		case <-done:
+			// Fn can return right as the deadline passes, leaving both
+			// cases ready; the attempt still timed out.
+			if ctx.Err() != nil {
+				t.Errorf("tdag: node %s timed out after %s", n.ID, n.Timeout)
+			}
+		case <-ctx.Done():
+			t.Errorf("tdag: node %s timed out after %s", n.ID, n.Timeout)
+			// Fn has no way to be killed, only asked to stop via ctx.Ctx;
+			// if it ignores that, the goroutine above is still running
+			// and may still be holding onto &runCtx (and therefore t).
+			// Once this function returns, the attempt's subtest is done,
+			// and testing.T panics the whole binary if anything calls
+			// into it afterwards — so we have to wait the goroutine out
+			// before returning, even though its result no longer counts.
+			<-done
+			return &runCtx
+		}
+	} else {
+		<-done
 	}
+	return &runCtx
 }
 
 // collectDependencies recursively collects all dependencies for a given node.
@@ -321,9 +536,80 @@ func (d *TDag) collectDependencies(nodeID string, collected map[string]bool) {
 	}
 }
 
+// setStatus records the outcome of a node once it has run or been skipped.
+func (d *TDag) setStatus(id string, s nodeStatus) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	d.status[id] = s
+}
+
+// nodeStatus looks up the recorded outcome for a node.
+func (d *TDag) nodeStatus(id string) (nodeStatus, bool) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	s, ok := d.status[id]
+	return s, ok
+}
+
+// nodePassed reports whether a node ran and passed. The bool return value
+// is false if the node has not run yet.
+func (d *TDag) nodePassed(id string) (bool, bool) {
+	s, ok := d.nodeStatus(id)
+	return s == statusPassed, ok
+}
+
+// blockedBy reports whether a node should be skipped rather than run,
+// either because an upstream node whose FailurePolicy isn't
+// ContinueOnFail was skipped or failed and the edge from it doesn't have
+// SkipOK, or because one of its in-edges' constraints is not satisfied.
+func (d *TDag) blockedBy(id string, inEdges map[string][]*TEdge) (bool, string) {
+	for _, e := range inEdges[id] {
+		if status, ran := d.nodeStatus(e.Left.ID); ran && status != statusPassed {
+			if e.Left.FailurePolicy != ContinueOnFail && !e.SkipOK {
+				return true, fmt.Sprintf("upstream node %s did not pass", e.Left.ID)
+			}
+		}
+		for _, c := range e.Constraints {
+			ok, msg, err := c.Satisfied(d.Ctx, e.Left, e.Right)
+			if err != nil {
+				return true, err.Error()
+			}
+			if !ok {
+				return true, msg
+			}
+		}
+	}
+	return false, ""
+}
+
+// abort records that the run should stop scheduling new nodes, keeping
+// the first reason given.
+func (d *TDag) abort(reason string) {
+	d.abortMu.Lock()
+	defer d.abortMu.Unlock()
+	if !d.aborted {
+		d.aborted = true
+		d.abortReason = reason
+	}
+}
+
+// isAborted reports whether abort has been called, and with what reason.
+func (d *TDag) isAborted() (bool, string) {
+	d.abortMu.Lock()
+	defer d.abortMu.Unlock()
+	return d.aborted, d.abortReason
+}
+
+// createsCycle reports whether adding an edge from->to would create a
+// cycle, i.e. whether to can already reach from. Note the swapped
+// argument order in the detectCycle call below: it walks forward from to
+// looking for from, not the other way around - passing them in from->to
+// order here would check whether from can already reach to, which
+// rejects redundant edges like a->c next to a->b->c and lets real
+// cycles through.
 func (d *TDag) createsCycle(from, to *TNode) bool {
 	visited := make(map[string]bool)
-	return d.detectCycle(from, to, visited)
+	return d.detectCycle(to, from, visited)
 }
 
 func (d *TDag) detectCycle(start, target *TNode, visited map[string]bool) bool {
@@ -0,0 +1,219 @@
+package tdag
+
+import "fmt"
+
+// GraphTransformer mutates a TDag in place, modeled after Terraform's graph
+// transformer pattern: prune nodes, rewire edges, or reorder the graph
+// before it is scheduled.
+type GraphTransformer interface {
+	Transform(d *TDag) error
+}
+
+// TransformerPipeline runs an ordered list of GraphTransformers against a
+// TDag, stopping at the first error.
+type TransformerPipeline struct {
+	Transformers []GraphTransformer
+}
+
+// NewTransformerPipeline builds a TransformerPipeline from the given
+// transformers, run in the order given.
+func NewTransformerPipeline(transformers ...GraphTransformer) *TransformerPipeline {
+	return &TransformerPipeline{Transformers: transformers}
+}
+
+// Transform runs each transformer in order, stopping and returning the
+// first error encountered.
+func (p *TransformerPipeline) Transform(d *TDag) error {
+	for _, t := range p.Transformers {
+		if err := t.Transform(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply runs transformers against the graph in order. Call it before
+// RunTests/RunTo so the graph that gets scheduled reflects any pruning,
+// rewiring, or reordering the transformers perform.
+func (d *TDag) Apply(transformers ...GraphTransformer) error {
+	return NewTransformerPipeline(transformers...).Transform(d)
+}
+
+// NoopTag marks a node as a no-op for PruneNoopTransformer even though it
+// carries a non-nil Fn, e.g. a placeholder kept around only to anchor
+// edges during graph construction.
+const NoopTag = "noop"
+
+// PruneNoopTransformer drops nodes with no test function, or tagged
+// NoopTag, rewiring their in-edges directly to their out-edges so
+// downstream dependents are not orphaned.
+type PruneNoopTransformer struct{}
+
+// Transform removes every node whose Fn is nil or carries NoopTag,
+// reconnecting the graph around it.
+func (PruneNoopTransformer) Transform(d *TDag) error {
+	var noop []*TNode
+	for _, n := range d.Nodes {
+		if n.Fn == nil || hasTag(n, NoopTag) {
+			noop = append(noop, n)
+		}
+	}
+
+	for _, n := range noop {
+		var in, out []*TNode
+		for _, e := range d.Edges {
+			if e.Right.ID == n.ID {
+				in = append(in, e.Left)
+			}
+			if e.Left.ID == n.ID {
+				out = append(out, e.Right)
+			}
+		}
+
+		for _, u := range in {
+			for _, w := range out {
+				if u.ID == w.ID || d.hasEdge(u.ID, w.ID) || d.createsCycle(u, w) {
+					continue
+				}
+				d.Edges = append(d.Edges, &TEdge{Left: u, Right: w})
+			}
+		}
+
+		d.removeNode(n.ID)
+	}
+
+	return nil
+}
+
+// GraphVertexTransformer produces a replacement TestFn for a single node.
+type GraphVertexTransformer func(n *TNode) (TestFn, error)
+
+// VertexTransformer swaps a single node's Fn via a user-supplied
+// GraphVertexTransformer.
+type VertexTransformer struct {
+	NodeID string
+	Fn     GraphVertexTransformer
+}
+
+// Transform replaces the target node's Fn with the one returned by v.Fn.
+func (v *VertexTransformer) Transform(d *TDag) error {
+	node := d.findNodeByID(v.NodeID)
+	if node == nil {
+		return fmt.Errorf("node %s does not exist", v.NodeID)
+	}
+	fn, err := v.Fn(node)
+	if err != nil {
+		return err
+	}
+	node.Fn = fn
+	return nil
+}
+
+// CreateBeforeDestroyTransformer ensures a create node's work always
+// completes before its matching destroy node starts, adding the ordering
+// edge if the graph does not already encode it.
+type CreateBeforeDestroyTransformer struct {
+	CreateID  string
+	DestroyID string
+}
+
+// Transform adds an edge from CreateID to DestroyID if one is not already
+// present.
+func (c *CreateBeforeDestroyTransformer) Transform(d *TDag) error {
+	if d.hasEdge(c.CreateID, c.DestroyID) {
+		return nil
+	}
+	_, err := d.AddEdge(c.CreateID, c.DestroyID)
+	return err
+}
+
+// TransitiveReductionTransformer removes redundant edges A->C when a
+// longer path A->B->...->C already exists, leaving the schedule unchanged
+// while trimming edges that add nothing but noise.
+type TransitiveReductionTransformer struct{}
+
+// Transform drops every edge that is implied by a longer path between the
+// same two nodes.
+func (TransitiveReductionTransformer) Transform(d *TDag) error {
+	reachable := make(map[string]map[string]bool, len(d.Nodes))
+	for _, n := range d.Nodes {
+		reachable[n.ID] = d.reachableFrom(n.ID)
+	}
+
+	var kept []*TEdge
+	for _, e := range d.Edges {
+		redundant := false
+		for _, out := range d.outEdgesOf(e.Left.ID) {
+			if out.Right.ID == e.Right.ID {
+				continue
+			}
+			if reachable[out.Right.ID][e.Right.ID] {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			kept = append(kept, e)
+		}
+	}
+	d.Edges = kept
+	return nil
+}
+
+// outEdgesOf returns the edges leaving the node with the given id.
+func (d *TDag) outEdgesOf(id string) []*TEdge {
+	var out []*TEdge
+	for _, e := range d.Edges {
+		if e.Left.ID == id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// reachableFrom returns every node id reachable from id via one or more
+// edges.
+func (d *TDag) reachableFrom(id string) map[string]bool {
+	seen := make(map[string]bool)
+	var visit func(string)
+	visit = func(cur string) {
+		for _, e := range d.Edges {
+			if e.Left.ID == cur && !seen[e.Right.ID] {
+				seen[e.Right.ID] = true
+				visit(e.Right.ID)
+			}
+		}
+	}
+	visit(id)
+	return seen
+}
+
+// hasEdge reports whether an edge from from to to already exists.
+func (d *TDag) hasEdge(from, to string) bool {
+	for _, e := range d.Edges {
+		if e.Left.ID == from && e.Right.ID == to {
+			return true
+		}
+	}
+	return false
+}
+
+// removeNode drops the node with the given id along with every edge that
+// touches it.
+func (d *TDag) removeNode(id string) {
+	nodes := d.Nodes[:0]
+	for _, n := range d.Nodes {
+		if n.ID != id {
+			nodes = append(nodes, n)
+		}
+	}
+	d.Nodes = nodes
+
+	edges := d.Edges[:0]
+	for _, e := range d.Edges {
+		if e.Left.ID != id && e.Right.ID != id {
+			edges = append(edges, e)
+		}
+	}
+	d.Edges = edges
+}
@@ -0,0 +1,103 @@
+package tdag
+
+import "testing"
+
+func newTestTDag(t *testing.T) *TDag {
+	return NewTDag(t)
+}
+
+func TestCreatesCycleRejectsCycle(t *testing.T) {
+	d := newTestTDag(t)
+	d.AddNode("a", func(ctx *TestContext) {})
+	d.AddNode("b", func(ctx *TestContext) {})
+	d.AddNode("c", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.AddEdge("c", "a"); err == nil {
+		t.Fatal("expected adding c->a to be rejected as a cycle")
+	}
+}
+
+func TestPruneNoopTransformerDropsNilFn(t *testing.T) {
+	d := newTestTDag(t)
+	d.AddNode("a", func(ctx *TestContext) {})
+	d.AddNode("noop", nil)
+	d.AddNode("b", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("a", "noop"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("noop", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Apply(PruneNoopTransformer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes after pruning, got %d", len(d.Nodes))
+	}
+	if !d.hasEdge("a", "b") {
+		t.Fatalf("expected rewired edge a->b after pruning noop")
+	}
+}
+
+func TestPruneNoopTransformerDropsTaggedNode(t *testing.T) {
+	d := newTestTDag(t)
+	d.AddNode("a", func(ctx *TestContext) {})
+	d.AddNodeOpts("placeholder", func(ctx *TestContext) {}, WithTags(NoopTag))
+	d.AddNode("b", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("a", "placeholder"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("placeholder", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Apply(PruneNoopTransformer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes after pruning tagged noop, got %d", len(d.Nodes))
+	}
+	if !d.hasEdge("a", "b") {
+		t.Fatalf("expected rewired edge a->b after pruning tagged noop")
+	}
+}
+
+func TestTransitiveReductionTransformer(t *testing.T) {
+	d := newTestTDag(t)
+	d.AddNode("a", func(ctx *TestContext) {})
+	d.AddNode("b", func(ctx *TestContext) {})
+	d.AddNode("c", func(ctx *TestContext) {})
+
+	if _, err := d.AddEdge("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddEdge("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Apply(TransitiveReductionTransformer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.hasEdge("a", "c") {
+		t.Fatalf("expected redundant edge a->c to be removed")
+	}
+	if !d.hasEdge("a", "b") || !d.hasEdge("b", "c") {
+		t.Fatalf("expected a->b and b->c to survive transitive reduction")
+	}
+}